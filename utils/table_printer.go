@@ -1,32 +1,97 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 
-	"golang.org/x/crypto/ssh/terminal"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/term"
 )
 
 type TablePrinter interface {
 	IsTTY() bool
 	AddField(string, func(int, string) string, func(string) string)
+	AddFieldWithWeight(string, int, func(int, string) string, func(string) string)
 	EndRow()
+	// SetHeaderRow marks the row currently being built as a header row, so
+	// its columns are never truncated below their own width.
+	SetHeaderRow(bool)
 	Render() error
 }
 
-func NewTablePrinter(w io.Writer) TablePrinter {
+// defaultColumnWeight is used by AddField so existing callers keep today's
+// even-split layout.
+const defaultColumnWeight = 1
+
+// ttySizeFor reports whether w has a usable terminal size and, if so, what
+// it is. Resolution order: COLUMNS/LINES env vars (an override for test
+// harnesses and non-PTY CI environments that still want TTY-style output),
+// then w itself if it's an *os.File (terminal or not — an *os.File that
+// isn't a terminal, e.g. a redirected file, is conclusively not a TTY and
+// must not fall through to the /dev/tty probe below), then /dev/tty
+// directly in case w is a terminal wrapped by something that isn't an
+// *os.File at all, e.g. a buffered or color-stripping writer, mirroring the
+// approach fzf's renderer uses.
+func ttySizeFor(w io.Writer) (width, height int, isTTY bool) {
+	if cols, lines := envInt("COLUMNS"), envInt("LINES"); cols > 0 || lines > 0 {
+		if cols <= 0 {
+			cols = 80
+		}
+		return cols, lines, true
+	}
+
 	if outFile, isFile := w.(*os.File); isFile {
-		fd := int(outFile.Fd())
-		if terminal.IsTerminal(fd) {
-			ttyWidth := 80
-			if w, _, err := terminal.GetSize(fd); err == nil {
-				ttyWidth = w
-			}
-			return &ttyTablePrinter{
-				out:      w,
-				maxWidth: ttyWidth,
+		if term.IsTerminal(int(outFile.Fd())) {
+			width, height = 80, 24
+			if tw, th, err := term.GetSize(int(outFile.Fd())); err == nil {
+				width, height = tw, th
 			}
+			return width, height, true
+		}
+		return 0, 0, false
+	}
+
+	if tw, th, ok := probeControllingTTYSize(); ok {
+		return tw, th, true
+	}
+
+	return 0, 0, false
+}
+
+// ttyWidthFor reports whether w is a terminal and, if so, its current width
+// (falling back to 80 columns when the size can't be queried).
+func ttyWidthFor(w io.Writer) (width int, isTTY bool) {
+	width, _, isTTY = ttySizeFor(w)
+	return width, isTTY
+}
+
+// envInt parses name as a positive integer env var, returning 0 if it's
+// unset, empty, or not a valid positive integer.
+func envInt(name string) int {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
+
+func NewTablePrinter(w io.Writer) TablePrinter {
+	if width, isTTY := ttyWidthFor(w); isTTY {
+		return &ttyTablePrinter{
+			out:      w,
+			maxWidth: width,
 		}
 	}
 	return &tsvTablePrinter{
@@ -34,16 +99,101 @@ func NewTablePrinter(w io.Writer) TablePrinter {
 	}
 }
 
+// NewColorTablePrinter forces TTY-style rendering even when w is not a
+// terminal, which is useful when the caller already knows the output will
+// end up somewhere that understands ANSI colors, e.g. `less -R` or a CI log
+// viewer. When forceColor is false it behaves exactly like NewTablePrinter.
+func NewColorTablePrinter(w io.Writer, forceColor bool) TablePrinter {
+	if !forceColor {
+		return NewTablePrinter(w)
+	}
+
+	width, _ := ttyWidthFor(w)
+	if width == 0 {
+		width = 80
+	}
+	return &ttyTablePrinter{
+		out:      w,
+		maxWidth: width,
+	}
+}
+
+// Format selects the rendering strategy for NewTablePrinterWithFormat.
+type Format int
+
+const (
+	// FormatAuto picks FormatTTY when w is a terminal, FormatTSV otherwise -
+	// the same detection NewTablePrinter has always done.
+	FormatAuto Format = iota
+	FormatTTY
+	FormatTSV
+	FormatCSV
+	FormatJSON
+	FormatMarkdown
+	FormatBox
+)
+
+// NewTablePrinterWithFormat builds a TablePrinter that renders in the given
+// Format. headers, if non-empty, become the header row for formats that
+// need one (JSON object keys, Markdown/Box header rows); if left empty, the
+// first row ended with EndRow is promoted to the header row instead. TTY
+// and TSV output only use headers as an actual first row of text, so
+// callers that don't pass any keep today's behavior unchanged.
+func NewTablePrinterWithFormat(w io.Writer, format Format, headers []string) TablePrinter {
+	if format == FormatAuto {
+		format = FormatTSV
+		if _, isTTY := ttyWidthFor(w); isTTY {
+			format = FormatTTY
+		}
+	}
+
+	switch format {
+	case FormatTTY:
+		width, _ := ttyWidthFor(w)
+		if width == 0 {
+			width = 80
+		}
+		return withHeaderRow(&ttyTablePrinter{out: w, maxWidth: width}, headers)
+	case FormatCSV:
+		return &csvTablePrinter{out: w, bufferedRows: newBufferedRows(headers)}
+	case FormatJSON:
+		return &jsonTablePrinter{out: w, bufferedRows: newBufferedRows(headers)}
+	case FormatMarkdown:
+		return &markdownTablePrinter{out: w, bufferedRows: newBufferedRows(headers)}
+	case FormatBox:
+		return &boxTablePrinter{out: w, bufferedRows: newBufferedRows(headers)}
+	default:
+		return withHeaderRow(&tsvTablePrinter{out: w}, headers)
+	}
+}
+
+// withHeaderRow prepends headers as an actual header row for printers (TTY,
+// TSV) that render whatever rows they're given rather than tracking headers
+// separately.
+func withHeaderRow(p TablePrinter, headers []string) TablePrinter {
+	if len(headers) == 0 {
+		return p
+	}
+	p.SetHeaderRow(true)
+	for _, h := range headers {
+		p.AddField(h, nil, nil)
+	}
+	p.EndRow()
+	return p
+}
+
 type tableField struct {
 	Text         string
+	Weight       int
 	TruncateFunc func(int, string) string
 	ColorFunc    func(string) string
 }
 
 type ttyTablePrinter struct {
-	out      io.Writer
-	maxWidth int
-	rows     [][]tableField
+	out        io.Writer
+	maxWidth   int
+	rows       [][]tableField
+	headerRows map[int]bool
 }
 
 func (t ttyTablePrinter) IsTTY() bool {
@@ -51,15 +201,23 @@ func (t ttyTablePrinter) IsTTY() bool {
 }
 
 func (t *ttyTablePrinter) AddField(text string, truncateFunc func(int, string) string, colorFunc func(string) string) {
+	t.AddFieldWithWeight(text, defaultColumnWeight, truncateFunc, colorFunc)
+}
+
+func (t *ttyTablePrinter) AddFieldWithWeight(text string, weight int, truncateFunc func(int, string) string, colorFunc func(string) string) {
 	if truncateFunc == nil {
 		truncateFunc = truncate
 	}
+	if weight <= 0 {
+		weight = defaultColumnWeight
+	}
 	if t.rows == nil {
 		t.rows = [][]tableField{[]tableField{}}
 	}
 	rowI := len(t.rows) - 1
 	field := tableField{
 		Text:         text,
+		Weight:       weight,
 		TruncateFunc: truncateFunc,
 		ColorFunc:    colorFunc,
 	}
@@ -70,6 +228,19 @@ func (t *ttyTablePrinter) EndRow() {
 	t.rows = append(t.rows, []tableField{})
 }
 
+// SetHeaderRow marks the row currently being built (i.e. the one that will
+// be ended by the next EndRow) as a header row.
+func (t *ttyTablePrinter) SetHeaderRow(isHeader bool) {
+	if t.headerRows == nil {
+		t.headerRows = map[int]bool{}
+	}
+	rowI := len(t.rows) - 1
+	if rowI < 0 {
+		rowI = 0
+	}
+	t.headerRows[rowI] = isHeader
+}
+
 func (t *ttyTablePrinter) Render() error {
 	if len(t.rows) == 0 {
 		return nil
@@ -77,31 +248,41 @@ func (t *ttyTablePrinter) Render() error {
 
 	numCols := len(t.rows[0])
 	colWidths := make([]int, numCols)
-	// measure maximum content width per column
+	colWeights := make([]int, numCols)
+	// measure maximum content width and weight per column
 	for _, row := range t.rows {
 		for col, field := range row {
-			textLen := len(field.Text)
+			textLen := displayWidth(field.Text)
 			if textLen > colWidths[col] {
 				colWidths[col] = textLen
 			}
+			if field.Weight > colWeights[col] {
+				colWeights[col] = field.Weight
+			}
+		}
+	}
+	for col := range colWeights {
+		if colWeights[col] <= 0 {
+			colWeights[col] = defaultColumnWeight
 		}
 	}
 
 	delim := "  "
 	availWidth := t.maxWidth - colWidths[0] - ((numCols - 1) * len(delim))
-	// add extra space from columns that are already narrower than threshold
-	for col := 1; col < numCols; col++ {
-		availColWidth := availWidth / (numCols - 1)
-		if extra := availColWidth - colWidths[col]; extra > 0 {
-			availWidth += extra
-		}
+	if numCols > 1 && availWidth > 0 {
+		distributeColumnWidths(colWidths, colWeights, availWidth, numCols)
 	}
-	// cap all but first column to fit available terminal width
-	// TODO: support weighted instead of even redistribution
-	for col := 1; col < numCols; col++ {
-		availColWidth := availWidth / (numCols - 1)
-		if colWidths[col] > availColWidth {
-			colWidths[col] = availColWidth
+
+	// headers are never truncated below their own natural width, even if
+	// that means the row runs past the terminal width
+	for rowI, isHeader := range t.headerRows {
+		if !isHeader || rowI >= len(t.rows) {
+			continue
+		}
+		for col, field := range t.rows[rowI] {
+			if hw := displayWidth(field.Text); hw > colWidths[col] {
+				colWidths[col] = hw
+			}
 		}
 	}
 
@@ -115,8 +296,10 @@ func (t *ttyTablePrinter) Render() error {
 			}
 			truncVal := field.TruncateFunc(colWidths[col], field.Text)
 			if col < numCols-1 {
-				// pad value with spaces on the right
-				truncVal = fmt.Sprintf("%-*s", colWidths[col], truncVal)
+				// pad value with spaces on the right, accounting for display width
+				if padWidth := colWidths[col] - displayWidth(truncVal); padWidth > 0 {
+					truncVal += strings.Repeat(" ", padWidth)
+				}
 			}
 			if field.ColorFunc != nil {
 				truncVal = field.ColorFunc(truncVal)
@@ -136,6 +319,50 @@ func (t *ttyTablePrinter) Render() error {
 	return nil
 }
 
+// distributeColumnWidths caps colWidths[1:] to fit availWidth, giving each
+// column a share proportional to its weight. Columns whose natural width is
+// already smaller than their share settle immediately and hand their slack
+// back to the columns that are still overflowing; this repeats until no
+// column settles in a pass.
+func distributeColumnWidths(colWidths, colWeights []int, availWidth, numCols int) {
+	settled := make([]bool, numCols)
+	remainingWidth := availWidth
+	remainingWeight := 0
+	for col := 1; col < numCols; col++ {
+		remainingWeight += colWeights[col]
+	}
+
+	for {
+		changed := false
+		for col := 1; col < numCols; col++ {
+			if settled[col] || remainingWeight == 0 {
+				continue
+			}
+			share := remainingWidth * colWeights[col] / remainingWeight
+			if colWidths[col] <= share {
+				settled[col] = true
+				remainingWidth -= colWidths[col]
+				remainingWeight -= colWeights[col]
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	for col := 1; col < numCols; col++ {
+		if settled[col] {
+			continue
+		}
+		if remainingWeight == 0 {
+			colWidths[col] = 0
+			continue
+		}
+		colWidths[col] = remainingWidth * colWeights[col] / remainingWeight
+	}
+}
+
 type tsvTablePrinter struct {
 	out        io.Writer
 	currentCol int
@@ -145,7 +372,11 @@ func (t tsvTablePrinter) IsTTY() bool {
 	return false
 }
 
-func (t *tsvTablePrinter) AddField(text string, _ func(int, string) string, _ func(string) string) {
+func (t *tsvTablePrinter) AddField(text string, truncateFunc func(int, string) string, colorFunc func(string) string) {
+	t.AddFieldWithWeight(text, defaultColumnWeight, truncateFunc, colorFunc)
+}
+
+func (t *tsvTablePrinter) AddFieldWithWeight(text string, _ int, _ func(int, string) string, _ func(string) string) {
 	if t.currentCol > 0 {
 		fmt.Fprint(t.out, "\t")
 	}
@@ -158,13 +389,669 @@ func (t *tsvTablePrinter) EndRow() {
 	t.currentCol = 0
 }
 
+// SetHeaderRow is a no-op for TSV output: there's no column truncation to
+// protect a header from.
+func (t *tsvTablePrinter) SetHeaderRow(bool) {}
+
 func (t *tsvTablePrinter) Render() error {
 	return nil
 }
 
+// OverflowPolicy controls what a StreamingTablePrinter does with a row that
+// arrives after column widths have already been locked in and doesn't fit.
+type OverflowPolicy int
+
+const (
+	// OverflowTruncate keeps every column at its locked-in width, which only
+	// changes when WatchResize picks up a terminal resize; rows that don't
+	// fit are truncated exactly like ttyTablePrinter does.
+	OverflowTruncate OverflowPolicy = iota
+	// OverflowWiden grows a column's locked width in place instead of
+	// truncating. Rows already flushed are not reflowed, so this trades
+	// perfect alignment for never losing data.
+	OverflowWiden
+)
+
+// StreamingTablePrinter renders rows as they arrive instead of buffering the
+// whole table in memory. It samples the first sampleRows rows to decide
+// column widths, flushes them, then writes every later row immediately
+// using those locked-in widths.
+type StreamingTablePrinter struct {
+	out        io.Writer
+	maxWidth   int
+	sampleRows int
+	overflow   OverflowPolicy
+
+	sample     [][]tableField
+	headerRows map[int]bool
+
+	mu            sync.Mutex
+	locked        bool
+	naturalWidths []int
+	colWeights    []int
+	colWidths     []int
+	current       []tableField
+	err           error
+
+	stopWatching func()
+}
+
+// NewStreamingTablePrinter creates a StreamingTablePrinter writing to w.
+// sampleRows rows are buffered before the first flush to compute column
+// widths; values less than 1 are treated as 1.
+func NewStreamingTablePrinter(w io.Writer, sampleRows int) *StreamingTablePrinter {
+	width, _ := ttyWidthFor(w)
+	if width == 0 {
+		width = 80
+	}
+	if sampleRows < 1 {
+		sampleRows = 1
+	}
+	return &StreamingTablePrinter{
+		out:        w,
+		maxWidth:   width,
+		sampleRows: sampleRows,
+	}
+}
+
+// SetOverflowPolicy controls how rows that arrive after widths are locked
+// are handled when they don't fit. The default is OverflowTruncate.
+func (t *StreamingTablePrinter) SetOverflowPolicy(policy OverflowPolicy) {
+	t.overflow = policy
+}
+
+func (t *StreamingTablePrinter) IsTTY() bool {
+	return true
+}
+
+func (t *StreamingTablePrinter) AddField(text string, truncateFunc func(int, string) string, colorFunc func(string) string) {
+	t.AddFieldWithWeight(text, defaultColumnWeight, truncateFunc, colorFunc)
+}
+
+func (t *StreamingTablePrinter) AddFieldWithWeight(text string, weight int, truncateFunc func(int, string) string, colorFunc func(string) string) {
+	if truncateFunc == nil {
+		truncateFunc = truncate
+	}
+	if weight <= 0 {
+		weight = defaultColumnWeight
+	}
+	field := tableField{
+		Text:         text,
+		Weight:       weight,
+		TruncateFunc: truncateFunc,
+		ColorFunc:    colorFunc,
+	}
+	if t.locked {
+		t.current = append(t.current, field)
+		return
+	}
+	if t.sample == nil {
+		t.sample = [][]tableField{[]tableField{}}
+	}
+	rowI := len(t.sample) - 1
+	t.sample[rowI] = append(t.sample[rowI], field)
+}
+
+// SetHeaderRow marks the row currently being built as a header row, so its
+// columns are never truncated below their own width. It only has an effect
+// before widths are locked in.
+func (t *StreamingTablePrinter) SetHeaderRow(isHeader bool) {
+	if t.locked {
+		return
+	}
+	if t.headerRows == nil {
+		t.headerRows = map[int]bool{}
+	}
+	rowI := len(t.sample) - 1
+	if rowI < 0 {
+		rowI = 0
+	}
+	t.headerRows[rowI] = isHeader
+}
+
+func (t *StreamingTablePrinter) EndRow() {
+	if t.locked {
+		t.writeRow(t.current)
+		t.current = nil
+		return
+	}
+	t.sample = append(t.sample, []tableField{})
+	if len(t.sample)-1 >= t.sampleRows {
+		t.lockWidths()
+	}
+}
+
+// Flush locks in column widths from whatever rows have been buffered so far
+// and writes them out, without waiting for sampleRows rows to accumulate.
+// It is a no-op once widths are already locked.
+func (t *StreamingTablePrinter) Flush() error {
+	if !t.locked {
+		if len(t.sample) > 0 && len(t.sample[len(t.sample)-1]) > 0 {
+			t.sample = append(t.sample, []tableField{})
+		}
+		if len(t.sample) > 0 {
+			t.lockWidths()
+		}
+	}
+	return t.err
+}
+
+// Render flushes any still-buffered sample rows. Rows added after widths
+// were locked have already been written by EndRow, so there is nothing left
+// to do for them.
+func (t *StreamingTablePrinter) Render() error {
+	return t.Flush()
+}
+
+func (t *StreamingTablePrinter) lockWidths() {
+	completeRows := t.sample[:len(t.sample)-1]
+	if len(completeRows) == 0 {
+		t.mu.Lock()
+		t.locked = true
+		t.mu.Unlock()
+		return
+	}
+
+	numCols := len(completeRows[0])
+	colWidths := make([]int, numCols)
+	colWeights := make([]int, numCols)
+	for _, row := range completeRows {
+		for col, field := range row {
+			if w := displayWidth(field.Text); w > colWidths[col] {
+				colWidths[col] = w
+			}
+			if field.Weight > colWeights[col] {
+				colWeights[col] = field.Weight
+			}
+		}
+	}
+	for col := range colWeights {
+		if colWeights[col] <= 0 {
+			colWeights[col] = defaultColumnWeight
+		}
+	}
+
+	naturalWidths := append([]int(nil), colWidths...)
+
+	t.mu.Lock()
+	maxWidth := t.maxWidth
+	t.mu.Unlock()
+
+	delim := "  "
+	availWidth := maxWidth - colWidths[0] - ((numCols - 1) * len(delim))
+	if numCols > 1 && availWidth > 0 {
+		distributeColumnWidths(colWidths, colWeights, availWidth, numCols)
+	}
+	for rowI, isHeader := range t.headerRows {
+		if !isHeader || rowI >= len(completeRows) {
+			continue
+		}
+		for col, field := range completeRows[rowI] {
+			if hw := displayWidth(field.Text); hw > colWidths[col] {
+				colWidths[col] = hw
+			}
+		}
+	}
+
+	t.mu.Lock()
+	t.naturalWidths = naturalWidths
+	t.colWeights = colWeights
+	t.colWidths = colWidths
+	t.locked = true
+	t.mu.Unlock()
+
+	for _, row := range completeRows {
+		t.writeRow(row)
+	}
+	t.sample = nil
+}
+
+// WatchResize starts watching for terminal resize events (SIGWINCH on
+// Unix; polled via the console API on Windows) and, between flushes,
+// re-distributes column widths to fit the new size. It returns a function
+// that stops watching; callers should defer it. Calling WatchResize again
+// replaces the previous watch.
+func (t *StreamingTablePrinter) WatchResize() (stop func()) {
+	if t.stopWatching != nil {
+		t.stopWatching()
+	}
+	stop = watchResize(t.out, t.applyResize)
+	t.stopWatching = stop
+	return stop
+}
+
+// applyResize updates maxWidth and, if column widths are already locked,
+// re-runs distributeColumnWidths against the original natural widths so
+// rows written after this point fit the new terminal size.
+func (t *StreamingTablePrinter) applyResize(width int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.maxWidth = width
+	if !t.locked || len(t.naturalWidths) == 0 {
+		return
+	}
+
+	numCols := len(t.naturalWidths)
+	colWidths := append([]int(nil), t.naturalWidths...)
+	colWeights := append([]int(nil), t.colWeights...)
+
+	delim := "  "
+	availWidth := width - colWidths[0] - ((numCols - 1) * len(delim))
+	if numCols > 1 && availWidth > 0 {
+		distributeColumnWidths(colWidths, colWeights, availWidth, numCols)
+	}
+	t.colWidths = colWidths
+}
+
+// writeRow renders a single row using the locked-in column widths,
+// resolving any overflow per t.overflow. Errors are sticky: once one write
+// fails, later calls become no-ops and Render/Flush surface the error.
+func (t *StreamingTablePrinter) writeRow(row []tableField) {
+	if t.err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	colWidths := append([]int(nil), t.colWidths...)
+	t.mu.Unlock()
+
+	delim := "  "
+	numCols := len(colWidths)
+	widened := false
+	for col := 0; col < numCols; col++ {
+		var field tableField
+		if col < len(row) {
+			field = row[col]
+		}
+		if col > 0 {
+			if _, err := fmt.Fprint(t.out, delim); err != nil {
+				t.err = err
+				return
+			}
+		}
+
+		width := colWidths[col]
+		if t.overflow == OverflowWiden {
+			if w := displayWidth(field.Text); w > width {
+				width = w
+				colWidths[col] = w
+				widened = true
+			}
+		}
+
+		truncateFunc := field.TruncateFunc
+		if truncateFunc == nil {
+			truncateFunc = truncate
+		}
+		truncVal := truncateFunc(width, field.Text)
+		if col < numCols-1 {
+			if pad := width - displayWidth(truncVal); pad > 0 {
+				truncVal += strings.Repeat(" ", pad)
+			}
+		}
+		if field.ColorFunc != nil {
+			truncVal = field.ColorFunc(truncVal)
+		}
+		if _, err := fmt.Fprint(t.out, truncVal); err != nil {
+			t.err = err
+			return
+		}
+	}
+	if _, err := fmt.Fprint(t.out, "\n"); err != nil {
+		t.err = err
+	}
+	if widened {
+		t.mu.Lock()
+		t.colWidths = colWidths
+		t.mu.Unlock()
+	}
+}
+
+// bufferedRows is embedded by the scripting-oriented printers (CSV, JSON,
+// Markdown, Box), which all need every row in hand before they can render
+// anything. It implements everything but IsTTY and Render.
+type bufferedRows struct {
+	rows         [][]tableField
+	headers      []string
+	headersGiven bool
+	firstRowDone bool
+}
+
+func newBufferedRows(headers []string) bufferedRows {
+	b := bufferedRows{}
+	if len(headers) > 0 {
+		b.headers = append([]string{}, headers...)
+		b.headersGiven = true
+	}
+	return b
+}
+
+func (b *bufferedRows) AddField(text string, truncateFunc func(int, string) string, colorFunc func(string) string) {
+	b.AddFieldWithWeight(text, defaultColumnWeight, truncateFunc, colorFunc)
+}
+
+func (b *bufferedRows) AddFieldWithWeight(text string, weight int, truncateFunc func(int, string) string, colorFunc func(string) string) {
+	if weight <= 0 {
+		weight = defaultColumnWeight
+	}
+	if b.rows == nil {
+		b.rows = [][]tableField{[]tableField{}}
+	}
+	rowI := len(b.rows) - 1
+	b.rows[rowI] = append(b.rows[rowI], tableField{
+		Text:         text,
+		Weight:       weight,
+		TruncateFunc: truncateFunc,
+		ColorFunc:    colorFunc,
+	})
+}
+
+func (b *bufferedRows) EndRow() {
+	if b.rows == nil {
+		b.rows = [][]tableField{[]tableField{}}
+	}
+	if !b.firstRowDone {
+		b.firstRowDone = true
+		if !b.headersGiven && len(b.rows) == 1 && len(b.rows[0]) > 0 {
+			for _, f := range b.rows[0] {
+				b.headers = append(b.headers, f.Text)
+			}
+			b.rows = b.rows[:0]
+		}
+	}
+	b.rows = append(b.rows, []tableField{})
+}
+
+// SetHeaderRow is a no-op: headers come from NewTablePrinterWithFormat's
+// headers argument, or are inferred from the first row.
+func (b *bufferedRows) SetHeaderRow(bool) {}
+
+// columnName returns the header for column i, or a synthetic name if fewer
+// headers than columns were supplied.
+func columnName(headers []string, i int) string {
+	if i < len(headers) {
+		return headers[i]
+	}
+	return fmt.Sprintf("col%d", i+1)
+}
+
+type csvTablePrinter struct {
+	out io.Writer
+	bufferedRows
+}
+
+func (p *csvTablePrinter) IsTTY() bool { return false }
+
+func (p *csvTablePrinter) Render() error {
+	cw := csv.NewWriter(p.out)
+	if len(p.headers) > 0 {
+		if err := cw.Write(p.headers); err != nil {
+			return err
+		}
+	}
+	for _, row := range p.rows {
+		if len(row) == 0 {
+			continue
+		}
+		record := make([]string, len(row))
+		for i, f := range row {
+			record[i] = f.Text
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type jsonTablePrinter struct {
+	out io.Writer
+	bufferedRows
+}
+
+func (p *jsonTablePrinter) IsTTY() bool { return false }
+
+func (p *jsonTablePrinter) Render() error {
+	objects := make([]json.RawMessage, 0, len(p.rows))
+	for _, row := range p.rows {
+		if len(row) == 0 {
+			continue
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, f := range row {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(columnName(p.headers, i))
+			if err != nil {
+				return err
+			}
+			val, err := json.Marshal(f.Text)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(val)
+		}
+		buf.WriteByte('}')
+		objects = append(objects, json.RawMessage(buf.Bytes()))
+	}
+	return json.NewEncoder(p.out).Encode(objects)
+}
+
+type markdownTablePrinter struct {
+	out io.Writer
+	bufferedRows
+}
+
+func (p *markdownTablePrinter) IsTTY() bool { return false }
+
+func (p *markdownTablePrinter) Render() error {
+	numCols := len(p.headers)
+	if numCols == 0 && len(p.rows) > 0 {
+		numCols = len(p.rows[0])
+	}
+	if numCols == 0 {
+		return nil
+	}
+
+	headers := p.headers
+	if len(headers) == 0 {
+		headers = make([]string, numCols)
+		for i := range headers {
+			headers[i] = columnName(nil, i)
+		}
+	}
+	if _, err := fmt.Fprintf(p.out, "| %s |\n", strings.Join(headers, " | ")); err != nil {
+		return err
+	}
+	aligns := make([]string, numCols)
+	for i := range aligns {
+		aligns[i] = "---"
+	}
+	if _, err := fmt.Fprintf(p.out, "| %s |\n", strings.Join(aligns, " | ")); err != nil {
+		return err
+	}
+
+	for _, row := range p.rows {
+		if len(row) == 0 {
+			continue
+		}
+		cells := make([]string, len(row))
+		for i, f := range row {
+			cells[i] = markdownEscape(f.Text)
+		}
+		if _, err := fmt.Fprintf(p.out, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\n", " ")
+	return s
+}
+
+type boxTablePrinter struct {
+	out io.Writer
+	bufferedRows
+}
+
+func (p *boxTablePrinter) IsTTY() bool { return false }
+
+func (p *boxTablePrinter) Render() error {
+	dataRows := make([][]string, 0, len(p.rows))
+	for _, row := range p.rows {
+		if len(row) == 0 {
+			continue
+		}
+		cells := make([]string, len(row))
+		for i, f := range row {
+			cells[i] = f.Text
+		}
+		dataRows = append(dataRows, cells)
+	}
+
+	numCols := len(p.headers)
+	if numCols == 0 && len(dataRows) > 0 {
+		numCols = len(dataRows[0])
+	}
+	if numCols == 0 {
+		return nil
+	}
+
+	colWidths := make([]int, numCols)
+	for i, h := range p.headers {
+		if w := displayWidth(h); w > colWidths[i] {
+			colWidths[i] = w
+		}
+	}
+	for _, row := range dataRows {
+		for i, c := range row {
+			if w := displayWidth(c); w > colWidths[i] {
+				colWidths[i] = w
+			}
+		}
+	}
+
+	drawBorder := func(left, mid, right string) error {
+		var b strings.Builder
+		b.WriteString(left)
+		for i, w := range colWidths {
+			b.WriteString(strings.Repeat("─", w+2))
+			if i < numCols-1 {
+				b.WriteString(mid)
+			}
+		}
+		b.WriteString(right + "\n")
+		_, err := fmt.Fprint(p.out, b.String())
+		return err
+	}
+	drawRow := func(cells []string) error {
+		var b strings.Builder
+		b.WriteString("│")
+		for i := 0; i < numCols; i++ {
+			var text string
+			if i < len(cells) {
+				text = cells[i]
+			}
+			pad := colWidths[i] - displayWidth(text)
+			b.WriteString(" " + text + strings.Repeat(" ", pad) + " │")
+		}
+		b.WriteString("\n")
+		_, err := fmt.Fprint(p.out, b.String())
+		return err
+	}
+
+	if err := drawBorder("┌", "┬", "┐"); err != nil {
+		return err
+	}
+	if len(p.headers) > 0 {
+		if err := drawRow(p.headers); err != nil {
+			return err
+		}
+		if err := drawBorder("├", "┼", "┤"); err != nil {
+			return err
+		}
+	}
+	for _, row := range dataRows {
+		if err := drawRow(row); err != nil {
+			return err
+		}
+	}
+	return drawBorder("└", "┴", "┘")
+}
+
+// ansiRE matches ANSI SGR (color) escape sequences, e.g. "\x1b[1;32m".
+var ansiRE = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// stripANSI removes ANSI SGR sequences so width calculations only see the
+// text a user would actually see on screen.
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\x1b[") {
+		return s
+	}
+	return ansiRE.ReplaceAllString(s, "")
+}
+
+// displayWidth returns the number of terminal cells s occupies, ignoring any
+// ANSI color codes. It takes a fast path for plain ASCII and only pays for
+// rune-width accounting once a non-ASCII byte (wide characters, combining
+// marks, emoji, ...) is seen.
+func displayWidth(s string) int {
+	s = stripANSI(s)
+	for i := 0; i < len(s); i++ {
+		if s[i] > 0x7F {
+			return runewidth.StringWidth(s)
+		}
+	}
+	return len(s)
+}
+
 func truncate(maxLength int, title string) string {
-	if len(title) > maxLength {
-		return title[0:maxLength-3] + "..."
+	if displayWidth(title) <= maxLength {
+		return title
+	}
+
+	const ellipsis = "..."
+	if maxLength < len(ellipsis) {
+		return ellipsis[0:maxLength]
+	}
+
+	targetWidth := maxLength - len(ellipsis)
+	r := []rune(title)
+	var b strings.Builder
+	w := 0
+	i := 0
+	for i < len(r) {
+		if r[i] == '\x1b' {
+			// copy ANSI escape sequences through untouched; they don't
+			// consume any of the visible width budget
+			j := i
+			for j < len(r) && r[j] != 'm' {
+				j++
+			}
+			if j < len(r) {
+				j++
+			}
+			b.WriteString(string(r[i:j]))
+			i = j
+			continue
+		}
+		cw := runewidth.RuneWidth(r[i])
+		if w+cw > targetWidth {
+			break
+		}
+		w += cw
+		b.WriteRune(r[i])
+		i++
 	}
-	return title
+	b.WriteString(ellipsis)
+	return b.String()
 }
\ No newline at end of file
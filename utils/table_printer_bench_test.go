@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+const benchRowCount = 100_000
+
+func writeBenchRows(b *testing.B, t TablePrinter) {
+	for i := 0; i < benchRowCount; i++ {
+		t.AddField(fmt.Sprintf("row-%d", i), nil, nil)
+		t.AddField("some column text", nil, nil)
+		t.AddField("another column of text to pad out the row", nil, nil)
+		t.EndRow()
+	}
+}
+
+// BenchmarkTTYTablePrinter exercises the buffering ttyTablePrinter, which
+// keeps every row in memory until Render is called. FormatTTY is forced
+// explicitly rather than going through NewTablePrinter, whose TTY detection
+// depends on whatever terminal (if any) is attached to the process running
+// the benchmark.
+func BenchmarkTTYTablePrinter(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		t := NewTablePrinterWithFormat(io.Discard, FormatTTY, nil)
+		writeBenchRows(b, t)
+		if err := t.Render(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkStreamingTablePrinter exercises StreamingTablePrinter, which
+// locks column widths after a small sample and writes each row as it
+// arrives, so memory use should stay roughly constant as row count grows.
+func BenchmarkStreamingTablePrinter(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		t := NewStreamingTablePrinter(io.Discard, 10)
+		writeBenchRows(b, t)
+		if err := t.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
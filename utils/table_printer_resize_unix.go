@@ -0,0 +1,36 @@
+//go:build !windows
+
+package utils
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchResize invokes onResize with w's current width every time the
+// process receives SIGWINCH, and returns a function that stops watching.
+func watchResize(w io.Writer, onResize func(width int)) func() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGWINCH)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-sig:
+				if width, isTTY := ttyWidthFor(w); isTTY {
+					onResize(width)
+				}
+			case <-done:
+				signal.Stop(sig)
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
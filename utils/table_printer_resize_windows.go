@@ -0,0 +1,40 @@
+//go:build windows
+
+package utils
+
+import (
+	"io"
+	"time"
+)
+
+// windowsResizePollInterval is how often we poll the console size on
+// Windows, which has no SIGWINCH equivalent delivered to Go programs.
+const windowsResizePollInterval = 500 * time.Millisecond
+
+// watchResize polls w's console size (via the same console API calls
+// term.GetSize already wraps) and invokes onResize whenever it changes. It
+// returns a function that stops watching.
+func watchResize(w io.Writer, onResize func(width int)) func() {
+	ticker := time.NewTicker(windowsResizePollInterval)
+	done := make(chan struct{})
+
+	go func() {
+		lastWidth := -1
+		for {
+			select {
+			case <-ticker.C:
+				if width, isTTY := ttyWidthFor(w); isTTY && width != lastWidth {
+					lastWidth = width
+					onResize(width)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import "testing"
+
+// combiningAcute is a decomposed "e" + combining acute accent (U+0301),
+// i.e. the "é" form of "é" rather than its precomposed single rune.
+const combiningAcute = "é"
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"ascii", "hello", 5},
+		{"cjk", "日本語", 6},
+		{"emoji", "😀", 2},
+		{"combining mark", combiningAcute, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := displayWidth(tt.text); got != tt.want {
+				t.Errorf("displayWidth(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxLength int
+		text      string
+		want      string
+	}{
+		{"ascii no truncation needed", 10, "hello", "hello"},
+		{"ascii truncated", 5, "hello world", "he..."},
+		{"cjk truncated to cell width", 5, "日本語テスト", "日..."},
+		{"emoji truncated", 5, "😀😀😀😀", "😀..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.maxLength, tt.text)
+			if got != tt.want {
+				t.Errorf("truncate(%d, %q) = %q, want %q", tt.maxLength, tt.text, got, tt.want)
+			}
+			if w := displayWidth(got); w > tt.maxLength {
+				t.Errorf("truncate(%d, %q) = %q, display width %d exceeds max", tt.maxLength, tt.text, got, w)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package utils
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// probeControllingTTYSize opens /dev/tty directly to find the terminal size
+// even when the table printer's writer isn't a *os.File but a controlling
+// terminal is still attached (e.g. stdout has been piped through a filter).
+func probeControllingTTYSize() (width, height int, ok bool) {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return 0, 0, false
+	}
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	if !term.IsTerminal(fd) {
+		return 0, 0, false
+	}
+	w, h, err := term.GetSize(fd)
+	if err != nil {
+		return 0, 0, false
+	}
+	return w, h, true
+}
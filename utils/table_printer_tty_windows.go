@@ -0,0 +1,10 @@
+//go:build windows
+
+package utils
+
+// probeControllingTTYSize has no /dev/tty equivalent on Windows; the
+// console's size is already reachable through the *os.File path in
+// ttySizeFor.
+func probeControllingTTYSize() (width, height int, ok bool) {
+	return 0, 0, false
+}